@@ -0,0 +1,44 @@
+package gocsi
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+)
+
+// NewClientCredentialsInjector returns a new unary client interceptor that
+// sets creds (the flat X_CSI_USER_CREDENTIALS map, or the --secrets-file
+// stanza scoped to the command being issued) into the secrets field of the
+// outgoing CSI request message, so the driver receives them the way the
+// CSI spec actually carries them rather than as a side channel it never
+// reads.
+func NewClientCredentialsInjector(creds map[string]string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, rep interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		if len(creds) > 0 {
+			switch treq := req.(type) {
+			case *csi.CreateVolumeRequest:
+				treq.ControllerCreateSecrets = creds
+			case *csi.DeleteVolumeRequest:
+				treq.ControllerDeleteSecrets = creds
+			case *csi.ControllerPublishVolumeRequest:
+				treq.ControllerPublishSecrets = creds
+			case *csi.ControllerUnpublishVolumeRequest:
+				treq.ControllerUnpublishSecrets = creds
+			case *csi.NodePublishVolumeRequest:
+				treq.NodePublishSecrets = creds
+			case *csi.NodeUnpublishVolumeRequest:
+				treq.NodeUnpublishSecrets = creds
+			}
+		}
+
+		return invoker(ctx, method, req, rep, cc, opts...)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/thecodeteam/gocsi"
+	"github.com/thecodeteam/gocsi/mock"
+)
+
+var mockOpts struct {
+	endpoint   string
+	configFile string
+}
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "starts an in-process mock CSI Identity/Controller/Node driver",
+	Long: `Starts an in-process mock CSI driver backed by an in-memory volume
+map. It is useful for exercising csc and its interceptors end-to-end
+without any external storage plug-in. Faults may be scripted per-RPC
+via --mock-config.`,
+	RunE: func(*cobra.Command, []string) error {
+		cfg, err := mock.LoadConfig(mockOpts.configFile)
+		if err != nil {
+			return err
+		}
+
+		proto, addr, err := gocsi.ParseProtoAddr(mockOpts.endpoint)
+		if err != nil {
+			return err
+		}
+		if proto == "unix" {
+			os.Remove(addr)
+		}
+		lis, err := net.Listen(proto, addr)
+		if err != nil {
+			return err
+		}
+
+		gsrv := grpc.NewServer()
+		mock.Register(gsrv, mock.New(cfg))
+
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigc
+			gsrv.GracefulStop()
+		}()
+
+		log.WithField("endpoint", mockOpts.endpoint).Info("serving mock CSI driver")
+		return gsrv.Serve(lis)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(mockCmd)
+
+	mockCmd.Flags().StringVar(
+		&mockOpts.endpoint,
+		"endpoint",
+		"unix:///tmp/csi.sock",
+		`The endpoint on which the mock driver listens`)
+
+	mockCmd.Flags().StringVar(
+		&mockOpts.configFile,
+		"mock-config",
+		os.Getenv("X_CSI_MOCK_CONFIG"),
+		`The path to a YAML or JSON document describing per-RPC faults to
+        inject (error codes, delays, one-shot failures). When unset the
+        mock driver injects no faults`)
+}
@@ -3,28 +3,39 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"text/template"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/thecodeteam/gocsi"
 )
 
 var debug, _ = strconv.ParseBool(os.Getenv("X_CSI_DEBUG"))
 
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
 var root struct {
-	ctx       context.Context
-	client    *grpc.ClientConn
-	tpl       *template.Template
-	userCreds map[string]string
+	ctx        context.Context
+	client     *grpc.ClientConn
+	tpl        *template.Template
+	userCreds  map[string]string
+	secretsDoc *secretsDoc
 
 	genMarkdown bool
 	logLevel    logLevelArg
@@ -35,6 +46,25 @@ var root struct {
 	version     csiVersionArg
 	metadata    mapOfStringArg
 
+	tlsCAFile     string
+	tlsCertFile   string
+	tlsKeyFile    string
+	tlsServerName string
+
+	metricsAddr string
+
+	retries         int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+
+	secretsFile string
+
+	logFormat     string
+	logFile       string
+	logMaxSize    int
+	logMaxBackups int
+	logMaxAge     int
+
 	withReqLogging bool
 	withRepLogging bool
 
@@ -64,13 +94,28 @@ var RootCmd = &cobra.Command{
 		if debug {
 			root.logLevel.Set(log.DebugLevel.String())
 			root.withReqLogging = true
-			root.withReqLogging = true
+			root.withRepLogging = true
 		}
 
 		// Set the log level.
 		lvl, _ := root.logLevel.Val()
 		log.SetLevel(lvl)
 
+		// Switch the log formatter if JSON output was requested.
+		if root.logFormat == logFormatJSON {
+			log.SetFormatter(&log.JSONFormatter{})
+		}
+
+		// Redirect logging to a rotating file if one was configured.
+		if root.logFile != "" {
+			log.SetOutput(&lumberjack.Logger{
+				Filename:   root.logFile,
+				MaxSize:    root.logMaxSize,
+				MaxBackups: root.logMaxBackups,
+				MaxAge:     root.logMaxAge,
+			})
+		}
+
 		if debug {
 			log.Warn("debug mode enabled")
 		}
@@ -103,9 +148,21 @@ var RootCmd = &cobra.Command{
 			root.tpl = tpl
 		}
 
-		// Parse the credentials if they exist.
+		// Parse the flat credentials if they exist. This remains the
+		// fallback used when --secrets-file/X_CSI_SECRETS_FILE is not
+		// provided or has no stanza for the current command.
 		root.userCreds = gocsi.ParseMap(os.Getenv("X_CSI_USER_CREDENTIALS"))
 
+		// Parse the per-operation secrets file if one was provided.
+		if root.secretsFile != "" {
+			doc, err := loadSecretsDoc(root.secretsFile)
+			if err != nil {
+				return err
+			}
+			root.secretsDoc = doc
+			log.WithField("path", root.secretsFile).Debug("loaded secrets file")
+		}
+
 		// Create the gRPC client connection.
 		opts := []grpc.DialOption{
 			grpc.WithDialer(
@@ -118,13 +175,89 @@ var RootCmd = &cobra.Command{
 				}),
 		}
 
-		// Disable TLS if specified.
+		// Disable TLS if specified, otherwise build transport credentials
+		// from the configured TLS material.
 		if root.insecure {
+			if root.tlsCAFile != "" || root.tlsCertFile != "" || root.tlsKeyFile != "" ||
+				root.tlsServerName != "" {
+				return errors.New(
+					"--insecure cannot be used with --tls-ca, --tls-cert, --tls-key, " +
+						"or --tls-server-name")
+			}
 			opts = append(opts, grpc.WithInsecure())
+		} else {
+			serverName := root.tlsServerName
+			if serverName == "" {
+				serverName = defaultTLSServerName(root.endpoint)
+			}
+			creds, err := transportCredentials(
+				root.tlsCAFile,
+				root.tlsCertFile,
+				root.tlsKeyFile,
+				serverName)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, grpc.WithTransportCredentials(creds))
+			log.Debug("enabled TLS transport credentials")
 		}
 
 		var iceptors []grpc.UnaryClientInterceptor
 
+		// Attach the credentials resolved for this command (the
+		// --secrets-file stanza scoped to cmd.Name() when provided,
+		// falling back to the flat X_CSI_USER_CREDENTIALS map) to every
+		// RPC this invocation issues.
+		creds := secretsForCmd(cmd.Name())
+		if len(creds) > 0 {
+			iceptors = append(iceptors, gocsi.NewClientCredentialsInjector(creds))
+			log.Debug("enabled credentials injector")
+		}
+
+		// Start the metrics server and enable the client-side collecting
+		// interceptor if a listen address was provided.
+		if root.metricsAddr != "" {
+			reg := prometheus.NewRegistry()
+			collector, err := gocsi.NewMetricsCollector(reg)
+			if err != nil {
+				return err
+			}
+
+			lis, err := net.Listen("tcp", root.metricsAddr)
+			if err != nil {
+				return fmt.Errorf("error binding --metrics-addr %s: %v", root.metricsAddr, err)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			go func() {
+				if err := http.Serve(lis, mux); err != nil {
+					log.WithError(err).Error("metrics server failed")
+				}
+			}()
+			log.WithField("addr", root.metricsAddr).Debug("started metrics server")
+
+			iceptors = append(iceptors, gocsi.NewClientMetricsInterceptor(collector))
+			log.Debug("enabled metrics interceptor")
+		}
+
+		// Configure automatic retries of transient CSI errors.
+		if root.retries > 0 {
+			var retryOpts []gocsi.RetryOption
+			retryOpts = append(retryOpts,
+				gocsi.WithRetryMax(root.retries),
+				gocsi.WithRetryBackoff(root.retryBackoff),
+				gocsi.WithRetryMaxBackoff(root.retryMaxBackoff))
+			if root.withSuccessCreateVolumeAlreadyExists {
+				retryOpts = append(retryOpts, gocsi.WithRetryCreateVolumeIdempotent())
+			}
+			if root.withSuccessDeleteVolumeNotFound {
+				retryOpts = append(retryOpts, gocsi.WithRetryDeleteVolumeIdempotent())
+			}
+			iceptors = append(iceptors, gocsi.NewClientRetryInterceptor(retryOpts...))
+			log.Debug("enabled retry interceptor")
+		}
+
 		// Configure logging.
 		if root.withReqLogging || root.withRepLogging {
 
@@ -149,6 +282,15 @@ var RootCmd = &cobra.Command{
 			}
 			iceptors = append(iceptors,
 				gocsi.NewClientLogger(loggingOpts...))
+
+			// When JSON output was requested, additionally emit one
+			// structured JSON object per RPC so operators can ship csc
+			// traces into a log pipeline.
+			if root.logFormat == logFormatJSON {
+				iceptors = append(iceptors,
+					gocsi.NewClientJSONLogger(log.StandardLogger().Out, creds))
+				log.Debug("enabled JSON RPC logger")
+			}
 		}
 
 		// Configure the spec validator.
@@ -290,6 +432,109 @@ func init() {
 		"v",
 		`The version sent with an RPC may be specified as MAJOR.MINOR.PATCH`)
 
+	RootCmd.PersistentFlags().StringVar(
+		&root.tlsCAFile,
+		"tls-ca",
+		os.Getenv("X_CSI_TLS_CA"),
+		`The path to a PEM-encoded CA certificate file used to verify the
+        server's certificate. If not specified the host's root CA set is
+        used`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.tlsCertFile,
+		"tls-cert",
+		os.Getenv("X_CSI_TLS_CERT"),
+		`The path to a PEM-encoded certificate file presented by the client
+        to enable mutual TLS`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.tlsKeyFile,
+		"tls-key",
+		os.Getenv("X_CSI_TLS_KEY"),
+		`The path to the PEM-encoded private key file that corresponds to
+        --tls-cert`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.tlsServerName,
+		"tls-server-name",
+		os.Getenv("X_CSI_TLS_SERVER_NAME"),
+		`Overrides the server name used to verify the certificate presented
+        by the server. Defaults to the host portion of --endpoint`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.metricsAddr,
+		"metrics-addr",
+		"",
+		`The TCP address on which to serve Prometheus metrics, ex. :8080.
+        When unset no metrics server is started and no metrics are
+        collected`)
+
+	RootCmd.PersistentFlags().IntVar(
+		&root.retries,
+		"retries",
+		0,
+		`The number of times to retry an RPC that fails with a transient
+        gRPC status code (Unavailable, ResourceExhausted, or
+        DeadlineExceeded). A value of zero disables retries`)
+
+	RootCmd.PersistentFlags().DurationVar(
+		&root.retryBackoff,
+		"retry-backoff",
+		100*time.Millisecond,
+		`The initial backoff duration used between retry attempts`)
+
+	RootCmd.PersistentFlags().DurationVar(
+		&root.retryMaxBackoff,
+		"retry-max-backoff",
+		time.Second,
+		`The maximum backoff duration used between retry attempts`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.secretsFile,
+		"secrets-file",
+		os.Getenv("X_CSI_SECRETS_FILE"),
+		`The path to a YAML or JSON document with per-operation secrets
+        stanzas (create_volume, delete_volume,
+        controller_publish_volume, controller_unpublish_volume,
+        node_publish_volume, node_unpublish_volume). When unset, or when
+        a command has no stanza, the flat X_CSI_USER_CREDENTIALS map is
+        used instead`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.logFormat,
+		"log-format",
+		logFormatText,
+		`The log output format, either "text" or "json". JSON output also
+        causes the request/response logging interceptors to emit one
+        structured object per RPC, with redacted secrets`)
+
+	RootCmd.PersistentFlags().StringVar(
+		&root.logFile,
+		"log-file",
+		"",
+		`The path to a file to which log output is redirected. When unset
+        log output is written to stderr`)
+
+	RootCmd.PersistentFlags().IntVar(
+		&root.logMaxSize,
+		"log-max-size",
+		100,
+		`The maximum size in megabytes of a --log-file before it is
+        rotated`)
+
+	RootCmd.PersistentFlags().IntVar(
+		&root.logMaxBackups,
+		"log-max-backups",
+		0,
+		`The maximum number of rotated --log-file backups to retain. A
+        value of zero retains all of them`)
+
+	RootCmd.PersistentFlags().IntVar(
+		&root.logMaxAge,
+		"log-max-age",
+		0,
+		`The maximum number of days to retain a rotated --log-file backup.
+        A value of zero does not time out backups`)
 }
 
 type logger struct {
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// secretsDoc is the per-operation shape of a --secrets-file document. Each
+// field holds the flat credentials map sent only for its corresponding
+// CSI RPC, mirroring Nomad's CSISecrets per-operation model.
+type secretsDoc struct {
+	CreateVolume              map[string]string `json:"create_volume,omitempty"`
+	DeleteVolume              map[string]string `json:"delete_volume,omitempty"`
+	ControllerPublishVolume   map[string]string `json:"controller_publish_volume,omitempty"`
+	ControllerUnpublishVolume map[string]string `json:"controller_unpublish_volume,omitempty"`
+	NodePublishVolume         map[string]string `json:"node_publish_volume,omitempty"`
+	NodeUnpublishVolume       map[string]string `json:"node_unpublish_volume,omitempty"`
+}
+
+// loadSecretsDoc reads a YAML or JSON --secrets-file document from path.
+func loadSecretsDoc(path string) (*secretsDoc, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &secretsDoc{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// secretsForCmd returns the secrets map scoped to cmdName, the Name() of
+// the cobra command about to issue an RPC. When no --secrets-file was
+// provided, or cmdName has no stanza in it, the flat
+// X_CSI_USER_CREDENTIALS map is used instead.
+func secretsForCmd(cmdName string) map[string]string {
+	if root.secretsDoc == nil {
+		return root.userCreds
+	}
+
+	var scoped map[string]string
+	switch cmdName {
+	case createVolumeCmd.Name():
+		scoped = root.secretsDoc.CreateVolume
+	case deleteVolumeCmd.Name():
+		scoped = root.secretsDoc.DeleteVolume
+	case controllerPublishVolumeCmd.Name():
+		scoped = root.secretsDoc.ControllerPublishVolume
+	case controllerUnpublishVolumeCmd.Name():
+		scoped = root.secretsDoc.ControllerUnpublishVolume
+	case nodePublishVolumeCmd.Name():
+		scoped = root.secretsDoc.NodePublishVolume
+	case nodeUnpublishVolumeCmd.Name():
+		scoped = root.secretsDoc.NodeUnpublishVolume
+	}
+
+	if scoped == nil {
+		return root.userCreds
+	}
+	return scoped
+}
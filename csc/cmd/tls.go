@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/thecodeteam/gocsi"
+)
+
+// transportCredentials builds the gRPC transport credentials used to
+// secure the connection to the CSI plug-in. certFile and keyFile are
+// optional; when both are provided the client presents them to the
+// server to enable mutual TLS. caFile is optional; when omitted the
+// host's root CA set is used to verify the server's certificate.
+func transportCredentials(
+	caFile, certFile, keyFile, serverName string) (credentials.TransportCredentials, error) {
+
+	tlsConfig := &tls.Config{ServerName: serverName}
+
+	if caFile != "" {
+		caCert, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading --tls-ca %s: %v", caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("error parsing --tls-ca %s: no certificates found", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be specified together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading --tls-cert/--tls-key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// defaultTLSServerName derives the --tls-server-name default from the host
+// portion of endpoint, so verification targets the name on the server's
+// certificate rather than the dial authority (which, for the default
+// unix-socket endpoint, is never the cert's host). It returns "" if
+// endpoint cannot be parsed or carries no host (ex. a unix socket path),
+// leaving ServerName unset.
+func defaultTLSServerName(endpoint string) string {
+	_, addr, err := gocsi.ParseProtoAddr(endpoint)
+	if err != nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,86 @@
+package gocsi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDFromOutgoingContext returns the request ID previously set on
+// ctx by NewClientRequestIDInjector, or "" if none is present.
+func requestIDFromOutgoingContext(ctx context.Context) string {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get(requestIDHeader)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// jsonLogEntry is written, one per line, by NewClientJSONLogger for
+// every RPC.
+type jsonLogEntry struct {
+	Method     string            `json:"method"`
+	RequestID  string            `json:"request_id,omitempty"`
+	DurationMS float64           `json:"duration_ms"`
+	Code       string            `json:"code"`
+	Secrets    map[string]string `json:"secrets,omitempty"`
+}
+
+const redactedSecretValue = "***redacted***"
+
+// redactSecrets returns a copy of secrets with every value replaced by
+// redactedSecretValue, preserving only the keys, so logs can record
+// which credentials were sent with an RPC without leaking their values
+// into a shipped log pipeline.
+func redactSecrets(secrets map[string]string) map[string]string {
+	if len(secrets) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(secrets))
+	for k := range secrets {
+		redacted[k] = redactedSecretValue
+	}
+	return redacted
+}
+
+// NewClientJSONLogger returns a new unary client interceptor that writes
+// w a single JSON object per RPC with the fields method, request_id,
+// duration_ms, code, and a redacted secrets field built from secrets.
+// secrets is the flat or per-operation-scoped credentials map resolved
+// for the command issuing the RPC.
+func NewClientJSONLogger(w io.Writer, secrets map[string]string) grpc.UnaryClientInterceptor {
+	enc := json.NewEncoder(w)
+	redacted := redactSecrets(secrets)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, rep interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		start := time.Now()
+		err := invoker(ctx, method, req, rep, cc, opts...)
+
+		entry := jsonLogEntry{
+			Method:     method,
+			RequestID:  requestIDFromOutgoingContext(ctx),
+			DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+			Code:       status.Code(err).String(),
+			Secrets:    redacted,
+		}
+		_ = enc.Encode(entry)
+
+		return err
+	}
+}
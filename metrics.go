@@ -0,0 +1,106 @@
+package gocsi
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsCollector bundles the Prometheus collectors shared by the client
+// and server metrics interceptors. It is safe to register the same
+// collector against multiple registries (for example the default registry
+// and a private one embedded in a test harness) as long as each registry
+// only sees it once.
+type MetricsCollector struct {
+	latency  *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	errors   *prometheus.CounterVec
+}
+
+// NewMetricsCollector creates a MetricsCollector and registers its
+// collectors with reg. The caller owns reg and is responsible for exposing
+// it, typically via promhttp.HandlerFor.
+func NewMetricsCollector(reg *prometheus.Registry) (*MetricsCollector, error) {
+	c := &MetricsCollector{
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "csi_rpc_duration_seconds",
+				Help:    "Latency of CSI RPCs in seconds, by method and status code.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "code"}),
+		inFlight: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "csi_rpc_in_flight",
+				Help: "Number of CSI RPCs currently in flight, by method.",
+			},
+			[]string{"method"}),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "csi_rpc_errors_total",
+				Help: "Total number of CSI RPCs that returned a non-OK status, by method and code.",
+			},
+			[]string{"method", "code"}),
+	}
+
+	for _, c := range []prometheus.Collector{c.latency, c.inFlight, c.errors} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *MetricsCollector) observe(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	c.latency.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(method, code).Inc()
+	}
+}
+
+// NewClientMetricsInterceptor returns a new unary client interceptor that
+// records per-RPC latency histograms, in-flight gauges, and error counters
+// against c, keyed by CSI method name and gRPC status code.
+func NewClientMetricsInterceptor(c *MetricsCollector) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, rep interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		c.inFlight.WithLabelValues(method).Inc()
+		defer c.inFlight.WithLabelValues(method).Dec()
+
+		start := time.Now()
+		err := invoker(ctx, method, req, rep, cc, opts...)
+		c.observe(method, start, err)
+		return err
+	}
+}
+
+// NewServerMetricsInterceptor returns a new unary server interceptor that
+// records per-RPC latency histograms, in-flight gauges, and error counters
+// against c, keyed by CSI method name and gRPC status code.
+func NewServerMetricsInterceptor(c *MetricsCollector) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		c.inFlight.WithLabelValues(info.FullMethod).Inc()
+		defer c.inFlight.WithLabelValues(info.FullMethod).Dec()
+
+		start := time.Now()
+		rep, err := handler(ctx, req)
+		c.observe(info.FullMethod, start, err)
+		return rep, err
+	}
+}
@@ -0,0 +1,120 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"google.golang.org/grpc/codes"
+)
+
+// Duration wraps time.Duration so a --mock-config document can specify a
+// delay as a human string (ex. "500ms") the same way --retry-backoff and
+// --retry-max-backoff do, as well as a raw nanosecond integer. ghodss/yaml
+// round-trips through encoding/json, so this one UnmarshalJSON covers
+// both YAML and JSON documents.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(t)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(time.Duration(t))
+	default:
+		return fmt.Errorf("invalid duration %v: must be a string or number", v)
+	}
+	return nil
+}
+
+// FaultConfig describes the scripted faults injected into a single RPC
+// method. An empty FaultConfig is a no-op: the call falls through to the
+// driver's normal in-memory behavior.
+type FaultConfig struct {
+	// ErrorCode is the gRPC status code returned instead of invoking the
+	// normal handler. A zero value (codes.OK) means no error is injected.
+	ErrorCode codes.Code `json:"errorCode,omitempty"`
+
+	// ErrorMsg is the message carried by ErrorCode, if set.
+	ErrorMsg string `json:"errorMsg,omitempty"`
+
+	// Delay is how long to sleep before handling the RPC, ex. "500ms".
+	Delay Duration `json:"delay,omitempty"`
+
+	// Once, when true, causes the fault to apply only to the first
+	// matching call; subsequent calls to the same method succeed
+	// normally.
+	Once bool `json:"once,omitempty"`
+
+	triggered bool
+}
+
+// Config is the root of a mock driver's fault-injection document. It maps
+// a CSI RPC's short method name (ex. "CreateVolume", "NodePublishVolume")
+// to the fault that should be injected for it. A Config is read and
+// mutated (Once faults flip FaultConfig.triggered) from whichever
+// goroutine is servicing a concurrent RPC, so fault lookups go through
+// mu.
+type Config struct {
+	Faults map[string]*FaultConfig `json:"faults,omitempty"`
+
+	mu sync.Mutex
+}
+
+// LoadConfig reads a YAML or JSON fault-injection document from path. A
+// path of "" returns an empty, no-fault Config.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{Faults: map[string]*FaultConfig{}}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// fault returns a copy of the fault configured for method, or nil if none
+// was configured or a one-shot fault has already been triggered.
+func (c *Config) fault(method string) *FaultConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.Faults[method]
+	if !ok || f == nil {
+		return nil
+	}
+	if f.Once && f.triggered {
+		return nil
+	}
+	f.triggered = true
+
+	cp := *f
+	return &cp
+}
@@ -0,0 +1,42 @@
+package mock
+
+import (
+	"context"
+	"net"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Register wires svc's Identity, Controller, and Node implementations
+// into gsrv.
+func Register(gsrv *grpc.Server, svc *Service) {
+	csi.RegisterIdentityServer(gsrv, svc)
+	csi.RegisterControllerServer(gsrv, svc)
+	csi.RegisterNodeServer(gsrv, svc)
+}
+
+// BufConnListener returns a bufconn.Listener with svc's Identity,
+// Controller, and Node implementations already registered and serving,
+// so tests can dial the mock driver intra-process without going through
+// a Unix socket or TCP port.
+func BufConnListener(svc *Service) (*bufconn.Listener, func()) {
+	const bufSize = 1024 * 1024
+
+	lis := bufconn.Listen(bufSize)
+	gsrv := grpc.NewServer()
+	Register(gsrv, svc)
+	go gsrv.Serve(lis)
+
+	return lis, gsrv.Stop
+}
+
+// BufConnDialer returns a grpc.WithContextDialer-compatible dialer that
+// connects to a listener previously returned by BufConnListener, for use
+// with grpc.DialContext(ctx, "bufconn", grpc.WithContextDialer(dialer), ...).
+func BufConnDialer(lis *bufconn.Listener) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}
+}
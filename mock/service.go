@@ -0,0 +1,268 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/status"
+)
+
+// Service is an in-process CSI Identity, Controller, and Node server
+// backed by an in-memory volume map. It is embedded directly into the
+// `csc mock` command and may also be dialed intra-process by tests via
+// BufConnDialer, letting contributors exercise interceptor changes
+// end-to-end without any external plugin.
+type Service struct {
+	name   string
+	vendor string
+	cfg    *Config
+
+	mu           sync.Mutex
+	volumes      map[string]*csi.Volume
+	publications map[string]string
+}
+
+// New creates a Service. cfg may be nil, in which case no faults are
+// ever injected.
+func New(cfg *Config) *Service {
+	if cfg == nil {
+		cfg = &Config{Faults: map[string]*FaultConfig{}}
+	}
+	return &Service{
+		name:         "mock.gocsi.thecodeteam.com",
+		vendor:       "gocsi",
+		cfg:          cfg,
+		volumes:      map[string]*csi.Volume{},
+		publications: map[string]string{},
+	}
+}
+
+// applyFault sleeps and/or returns the scripted error configured for
+// method, if any. Callers invoke it first thing in every RPC handler.
+func (s *Service) applyFault(method string) error {
+	f := s.cfg.fault(method)
+	if f == nil {
+		return nil
+	}
+	if f.Delay > 0 {
+		time.Sleep(time.Duration(f.Delay))
+	}
+	if f.ErrorCode != 0 {
+		return status.Error(f.ErrorCode, f.ErrorMsg)
+	}
+	return nil
+}
+
+//
+// Identity
+//
+
+// GetPluginInfo returns the mock driver's static name and vendor version.
+func (s *Service) GetPluginInfo(
+	ctx context.Context,
+	req *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+
+	if err := s.applyFault("GetPluginInfo"); err != nil {
+		return nil, err
+	}
+	return &csi.GetPluginInfoResponse{
+		Name:          s.name,
+		VendorVersion: "0.0.0",
+	}, nil
+}
+
+// GetPluginCapabilities reports that the mock driver implements the
+// Controller service.
+func (s *Service) GetPluginCapabilities(
+	ctx context.Context,
+	req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+
+	if err := s.applyFault("GetPluginCapabilities"); err != nil {
+		return nil, err
+	}
+	return &csi.GetPluginCapabilitiesResponse{
+		Capabilities: []*csi.PluginCapability{
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// Probe always reports the mock driver as ready.
+func (s *Service) Probe(
+	ctx context.Context,
+	req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+
+	if err := s.applyFault("Probe"); err != nil {
+		return nil, err
+	}
+	return &csi.ProbeResponse{}, nil
+}
+
+//
+// Controller
+//
+
+// CreateVolume creates a volume in the in-memory volume map, keyed by
+// the requested name.
+func (s *Service) CreateVolume(
+	ctx context.Context,
+	req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+
+	if err := s.applyFault("CreateVolume"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if vol, ok := s.volumes[req.Name]; ok {
+		return &csi.CreateVolumeResponse{Volume: vol}, nil
+	}
+
+	vol := &csi.Volume{
+		Id:            fmt.Sprintf("mock-vol-%d", len(s.volumes)+1),
+		CapacityBytes: req.GetCapacityRange().GetRequiredBytes(),
+		Attributes:    req.GetParameters(),
+	}
+	s.volumes[req.Name] = vol
+	return &csi.CreateVolumeResponse{Volume: vol}, nil
+}
+
+// DeleteVolume removes a volume from the in-memory volume map. Deleting
+// an unknown volume ID is treated as success, matching the CSI spec's
+// idempotency requirement.
+func (s *Service) DeleteVolume(
+	ctx context.Context,
+	req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+
+	if err := s.applyFault("DeleteVolume"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, vol := range s.volumes {
+		if vol.Id == req.VolumeId {
+			delete(s.volumes, name)
+			break
+		}
+	}
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+// ControllerPublishVolume records that volumeID is published to the
+// requested node in the in-memory publications map.
+func (s *Service) ControllerPublishVolume(
+	ctx context.Context,
+	req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+
+	if err := s.applyFault("ControllerPublishVolume"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.publications[req.VolumeId] = req.NodeId
+
+	return &csi.ControllerPublishVolumeResponse{
+		PublishVolumeInfo: map[string]string{"device": "/dev/mock"},
+	}, nil
+}
+
+// ControllerUnpublishVolume removes the in-memory publication recorded
+// for volumeID. Unpublishing an unknown volume ID is treated as success,
+// matching the CSI spec's idempotency requirement.
+func (s *Service) ControllerUnpublishVolume(
+	ctx context.Context,
+	req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+
+	if err := s.applyFault("ControllerUnpublishVolume"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.publications, req.VolumeId)
+	return &csi.ControllerUnpublishVolumeResponse{}, nil
+}
+
+// ControllerGetCapabilities reports that the mock driver supports
+// CreateDeleteVolume and PublishUnpublishVolume.
+func (s *Service) ControllerGetCapabilities(
+	ctx context.Context,
+	req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+
+	if err := s.applyFault("ControllerGetCapabilities"); err != nil {
+		return nil, err
+	}
+	return &csi.ControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.ControllerServiceCapability{
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+					},
+				},
+			},
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+//
+// Node
+//
+
+// NodePublishVolume is a no-op success; the mock driver does not touch
+// the filesystem.
+func (s *Service) NodePublishVolume(
+	ctx context.Context,
+	req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+
+	if err := s.applyFault("NodePublishVolume"); err != nil {
+		return nil, err
+	}
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// NodeUnpublishVolume is a no-op success; the mock driver does not touch
+// the filesystem.
+func (s *Service) NodeUnpublishVolume(
+	ctx context.Context,
+	req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+
+	if err := s.applyFault("NodeUnpublishVolume"); err != nil {
+		return nil, err
+	}
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+// NodeGetCapabilities reports that the mock driver has no optional node
+// capabilities.
+func (s *Service) NodeGetCapabilities(
+	ctx context.Context,
+	req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+
+	if err := s.applyFault("NodeGetCapabilities"); err != nil {
+		return nil, err
+	}
+	return &csi.NodeGetCapabilitiesResponse{}, nil
+}
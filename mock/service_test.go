@@ -0,0 +1,107 @@
+package mock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/thecodeteam/gocsi/mock"
+)
+
+func dial(t *testing.T, svc *mock.Service) (*grpc.ClientConn, func()) {
+	t.Helper()
+
+	lis, stop := mock.BufConnListener(svc)
+	cc, err := grpc.DialContext(
+		context.Background(),
+		"bufconn",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(mock.BufConnDialer(lis)))
+	if err != nil {
+		t.Fatalf("failed to dial mock driver: %v", err)
+	}
+
+	return cc, func() {
+		cc.Close()
+		stop()
+	}
+}
+
+func TestCreateAndDeleteVolume(t *testing.T) {
+	cc, done := dial(t, mock.New(nil))
+	defer done()
+
+	ctrl := csi.NewControllerClient(cc)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createRep, err := ctrl.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "vol1"})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	if createRep.Volume.Id == "" {
+		t.Fatal("CreateVolume returned an empty volume ID")
+	}
+
+	if _, err := ctrl.DeleteVolume(ctx, &csi.DeleteVolumeRequest{
+		VolumeId: createRep.Volume.Id,
+	}); err != nil {
+		t.Fatalf("DeleteVolume failed: %v", err)
+	}
+}
+
+func TestControllerPublishAndUnpublishVolume(t *testing.T) {
+	cc, done := dial(t, mock.New(nil))
+	defer done()
+
+	ctrl := csi.NewControllerClient(cc)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	createRep, err := ctrl.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "vol1"})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	pubRep, err := ctrl.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+		VolumeId: createRep.Volume.Id,
+		NodeId:   "node1",
+	})
+	if err != nil {
+		t.Fatalf("ControllerPublishVolume failed: %v", err)
+	}
+	if len(pubRep.PublishVolumeInfo) == 0 {
+		t.Fatal("ControllerPublishVolume returned empty publish volume info")
+	}
+
+	if _, err := ctrl.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+		VolumeId: createRep.Volume.Id,
+		NodeId:   "node1",
+	}); err != nil {
+		t.Fatalf("ControllerUnpublishVolume failed: %v", err)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	cfg := &mock.Config{
+		Faults: map[string]*mock.FaultConfig{
+			"CreateVolume": {ErrorCode: codes.ResourceExhausted, ErrorMsg: "out of space"},
+		},
+	}
+	cc, done := dial(t, mock.New(cfg))
+	defer done()
+
+	ctrl := csi.NewControllerClient(cc)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := ctrl.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "vol1"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
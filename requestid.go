@@ -0,0 +1,39 @@
+package gocsi
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader is the gRPC metadata key under which
+// NewClientRequestIDInjector stores the request ID, and the key
+// requestIDFromOutgoingContext reads back in jsonlog.go.
+const requestIDHeader = "csi.requestid"
+
+// NewClientRequestIDInjector returns a new unary client interceptor that
+// assigns each RPC it intercepts a monotonically increasing request ID,
+// attached to the outgoing gRPC metadata under requestIDHeader so later
+// interceptors in the chain (and the JSON RPC logger) can correlate a
+// request with its response.
+func NewClientRequestIDInjector() grpc.UnaryClientInterceptor {
+	var nextID uint64
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, rep interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		id := atomic.AddUint64(&nextID, 1)
+		ctx = metadata.AppendToOutgoingContext(
+			ctx, requestIDHeader, strconv.FormatUint(id, 10))
+
+		return invoker(ctx, method, req, rep, cc, opts...)
+	}
+}
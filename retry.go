@@ -0,0 +1,164 @@
+package gocsi
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createVolumeMethod and deleteVolumeMethod are the only CSI controller
+// RPCs for which a retry may have a visible side effect on the storage
+// backend, so they are excluded from automatic retries unless the caller
+// has opted in via WithRetryCreateVolumeIdempotent/WithRetryDeleteVolumeIdempotent.
+const (
+	createVolumeMethod = "/csi.v0.Controller/CreateVolume"
+	deleteVolumeMethod = "/csi.v0.Controller/DeleteVolume"
+)
+
+// retryableCodes are the gRPC status codes considered transient and
+// therefore eligible for an automatic retry.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.ResourceExhausted: true,
+	codes.DeadlineExceeded:  true,
+}
+
+type retryOpts struct {
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	idempotentCreateVolume bool
+	idempotentDeleteVolume bool
+}
+
+// RetryOption configures a client retry interceptor created with
+// NewClientRetryInterceptor.
+type RetryOption func(*retryOpts)
+
+// WithRetryMax sets the maximum number of times an RPC is retried. The
+// default is 3.
+func WithRetryMax(max int) RetryOption {
+	return func(o *retryOpts) {
+		o.maxRetries = max
+	}
+}
+
+// WithRetryBackoff sets the initial backoff duration used between retry
+// attempts. The default is 100 milliseconds.
+func WithRetryBackoff(d time.Duration) RetryOption {
+	return func(o *retryOpts) {
+		o.backoff = d
+	}
+}
+
+// WithRetryMaxBackoff caps the backoff duration calculated between retry
+// attempts. The default is one second.
+func WithRetryMaxBackoff(d time.Duration) RetryOption {
+	return func(o *retryOpts) {
+		o.maxBackoff = d
+	}
+}
+
+// WithRetryCreateVolumeIdempotent marks CreateVolume as safe to retry.
+// This should only be enabled alongside the spec validator's
+// WithSuccessCreateVolumeAlreadyExists option, since a retried
+// CreateVolume is only safe when a duplicate call returning
+// AlreadyExists is treated as success.
+func WithRetryCreateVolumeIdempotent() RetryOption {
+	return func(o *retryOpts) {
+		o.idempotentCreateVolume = true
+	}
+}
+
+// WithRetryDeleteVolumeIdempotent marks DeleteVolume as safe to retry.
+// This should only be enabled alongside the spec validator's
+// WithSuccessDeleteVolumeNotFound option, since a retried DeleteVolume is
+// only safe when a duplicate call returning NotFound is treated as
+// success.
+func WithRetryDeleteVolumeIdempotent() RetryOption {
+	return func(o *retryOpts) {
+		o.idempotentDeleteVolume = true
+	}
+}
+
+// NewClientRetryInterceptor returns a new unary client interceptor that
+// retries RPCs whose gRPC status code is Unavailable, ResourceExhausted,
+// or DeadlineExceeded using exponential backoff with jitter. CreateVolume
+// and DeleteVolume are never retried unless the caller has opted in via
+// WithRetryCreateVolumeIdempotent or WithRetryDeleteVolumeIdempotent. The
+// parent context's deadline is always honored: once it has expired, or
+// would expire before the next attempt, the last error is returned
+// unchanged.
+func NewClientRetryInterceptor(opts ...RetryOption) grpc.UnaryClientInterceptor {
+	o := &retryOpts{
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, rep interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption) error {
+
+		if method == createVolumeMethod && !o.idempotentCreateVolume {
+			return invoker(ctx, method, req, rep, cc, callOpts...)
+		}
+		if method == deleteVolumeMethod && !o.idempotentDeleteVolume {
+			return invoker(ctx, method, req, rep, cc, callOpts...)
+		}
+
+		backoff := o.backoff
+		var err error
+		for attempt := 0; attempt <= o.maxRetries; attempt++ {
+			err = invoker(ctx, method, req, rep, cc, callOpts...)
+			if err == nil {
+				return nil
+			}
+			if !retryableCodes[status.Code(err)] {
+				return err
+			}
+			if attempt == o.maxRetries {
+				return err
+			}
+
+			sleep := jitter(backoff)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= sleep {
+				return err
+			}
+
+			t := time.NewTimer(sleep)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+
+			backoff *= 2
+			if backoff > o.maxBackoff {
+				backoff = o.maxBackoff
+			}
+		}
+		return err
+	}
+}
+
+// jitter returns d plus or minus up to 20% of random jitter so that
+// concurrent clients retrying the same transient failure do not all
+// retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	delta := float64(d) * 0.2
+	return d - time.Duration(delta) + time.Duration(rand.Float64()*2*delta)
+}
@@ -0,0 +1,212 @@
+package gocsi_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/thecodeteam/gocsi"
+)
+
+// scriptedPingServer implements a single-method gRPC service used to
+// exercise the retry interceptor without depending on a real CSI plug-in.
+// Each call to Ping (also served as /csi.v0.Controller/CreateVolume) pops
+// the next status code off script and returns it; once the script is
+// exhausted OK is returned.
+type scriptedPingServer struct {
+	script []codes.Code
+	calls  int
+}
+
+func (s *scriptedPingServer) ping(
+	ctx context.Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+
+	var code codes.Code
+	if s.calls < len(s.script) {
+		code = s.script[s.calls]
+	}
+	s.calls++
+	if code == codes.OK {
+		return req, nil
+	}
+	return nil, status.Error(code, "scripted failure")
+}
+
+var pingServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gocsi_test.Ping",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler: func(
+				srv interface{},
+				ctx context.Context,
+				dec func(interface{}) error,
+				iceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(*scriptedPingServer).ping(ctx, req)
+			},
+		},
+	},
+}
+
+// createVolumeServiceDesc routes /csi.v0.Controller/CreateVolume to the
+// same scripted handler so the CreateVolume exclusion can be observed.
+var createVolumeServiceDesc = grpc.ServiceDesc{
+	ServiceName: "csi.v0.Controller",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateVolume",
+			Handler:    pingServiceDesc.Methods[0].Handler,
+		},
+	},
+}
+
+// startPingServer starts the scripted server on an ephemeral TCP port and
+// returns a client connection dialed against it along with a teardown
+// func.
+func startPingServer(
+	script []codes.Code,
+	iceptors ...grpc.UnaryClientInterceptor) (*grpc.ClientConn, *scriptedPingServer, func()) {
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	Ω(err).ShouldNot(HaveOccurred())
+
+	srv := &scriptedPingServer{script: script}
+	gsrv := grpc.NewServer()
+	gsrv.RegisterService(&pingServiceDesc, srv)
+	gsrv.RegisterService(&createVolumeServiceDesc, srv)
+	go gsrv.Serve(lis)
+
+	cc, err := grpc.Dial(
+		lis.Addr().String(),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(gocsi.ChainUnaryClient(iceptors...)))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	return cc, srv, func() {
+		cc.Close()
+		gsrv.Stop()
+		lis.Close()
+	}
+}
+
+var _ = Describe("NewClientRetryInterceptor", func() {
+	var (
+		cc      *grpc.ClientConn
+		srv     *scriptedPingServer
+		done    func()
+		callErr error
+		rep     *wrapperspb.StringValue
+	)
+
+	AfterEach(func() {
+		if done != nil {
+			done()
+		}
+		callErr = nil
+		rep = nil
+	})
+
+	invoke := func(ctx context.Context) {
+		req := &wrapperspb.StringValue{Value: "ping"}
+		rep = &wrapperspb.StringValue{}
+		callErr = cc.Invoke(ctx, "/gocsi_test.Ping/Ping", req, rep)
+	}
+
+	Context("When the RPC eventually succeeds", func() {
+		BeforeEach(func() {
+			cc, srv, done = startPingServer(
+				[]codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.OK},
+				gocsi.NewClientRetryInterceptor(
+					gocsi.WithRetryMax(3),
+					gocsi.WithRetryBackoff(time.Millisecond),
+					gocsi.WithRetryMaxBackoff(5*time.Millisecond)))
+			invoke(context.Background())
+		})
+		It("Should Retry Until Success", func() {
+			Ω(callErr).ShouldNot(HaveOccurred())
+			Ω(srv.calls).Should(Equal(3))
+		})
+	})
+
+	Context("When the retries are exhausted", func() {
+		BeforeEach(func() {
+			cc, srv, done = startPingServer(
+				[]codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable},
+				gocsi.NewClientRetryInterceptor(
+					gocsi.WithRetryMax(2),
+					gocsi.WithRetryBackoff(time.Millisecond),
+					gocsi.WithRetryMaxBackoff(5*time.Millisecond)))
+			invoke(context.Background())
+		})
+		It("Should Forward The Last Error Unchanged", func() {
+			Ω(callErr).Should(HaveOccurred())
+			Ω(status.Code(callErr)).Should(Equal(codes.Unavailable))
+			Ω(srv.calls).Should(Equal(3))
+		})
+	})
+
+	Context("When the status code is not retryable", func() {
+		BeforeEach(func() {
+			cc, srv, done = startPingServer(
+				[]codes.Code{codes.InvalidArgument},
+				gocsi.NewClientRetryInterceptor(gocsi.WithRetryMax(3)))
+			invoke(context.Background())
+		})
+		It("Should Not Retry", func() {
+			Ω(callErr).Should(HaveOccurred())
+			Ω(status.Code(callErr)).Should(Equal(codes.InvalidArgument))
+			Ω(srv.calls).Should(Equal(1))
+		})
+	})
+
+	Context("When the parent context expires before the next attempt", func() {
+		BeforeEach(func() {
+			cc, srv, done = startPingServer(
+				[]codes.Code{codes.Unavailable, codes.OK},
+				gocsi.NewClientRetryInterceptor(
+					gocsi.WithRetryMax(3),
+					gocsi.WithRetryBackoff(200*time.Millisecond),
+					gocsi.WithRetryMaxBackoff(time.Second)))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			invoke(ctx)
+		})
+		It("Should Not Retry Past The Deadline", func() {
+			Ω(callErr).Should(HaveOccurred())
+			Ω(srv.calls).Should(Equal(1))
+		})
+	})
+
+	Context("When CreateVolume is not marked idempotent", func() {
+		BeforeEach(func() {
+			cc, srv, done = startPingServer(
+				[]codes.Code{codes.Unavailable, codes.OK},
+				gocsi.NewClientRetryInterceptor(gocsi.WithRetryMax(3)))
+			req := &wrapperspb.StringValue{Value: "ping"}
+			rep = &wrapperspb.StringValue{}
+			callErr = cc.Invoke(
+				context.Background(),
+				"/csi.v0.Controller/CreateVolume",
+				req,
+				rep)
+		})
+		It("Should Not Retry", func() {
+			Ω(callErr).Should(HaveOccurred())
+			Ω(srv.calls).Should(Equal(1))
+		})
+	})
+})